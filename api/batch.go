@@ -0,0 +1,112 @@
+package api
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// BatchKVStore is an optional extension of KVStore that lets a host store
+// implement multi-key Get/Set/Delete as a single operation instead of N
+// independent calls (e.g. a single IAVL traversal instead of N), and report
+// a per-key error (e.g. a value too large, a write rejected by the store)
+// without failing the whole batch. Stores that don't implement it still work
+// correctly: cGetBatch/cSetBatch/cDeleteBatch fall back to looping over the
+// plain KVStore methods, which have no error return of their own, so the
+// fallback path always reports a nil error per key.
+type BatchKVStore interface {
+	// GetBatch returns one value (nil if missing) and one error per key, in
+	// the same order as keys.
+	GetBatch(keys [][]byte) (values [][]byte, errs []error)
+	// SetBatch sets keys[i] to values[i] for each i, returning one error per
+	// key so a subset of a batch can fail without aborting the rest.
+	SetBatch(keys, values [][]byte) (errs []error)
+	// DeleteBatch deletes each key, returning one error per key so a subset
+	// of a batch can fail without aborting the rest.
+	DeleteBatch(keys [][]byte) (errs []error)
+}
+
+// splitBatchEntries decodes a concatenated sequence of length-prefixed
+// entries (a 4-byte big-endian length followed by that many bytes) as used
+// by the batched DB callbacks to pass many keys (and values) across the FFI
+// boundary in a single call.
+func splitBatchEntries(data []byte) ([][]byte, error) {
+	var entries [][]byte
+	for len(data) > 0 {
+		if len(data) < 4 {
+			return nil, fmt.Errorf("truncated batch entry length prefix")
+		}
+		n := binary.BigEndian.Uint32(data[:4])
+		data = data[4:]
+		if uint64(len(data)) < uint64(n) {
+			return nil, fmt.Errorf("truncated batch entry: want %d bytes, have %d", n, len(data))
+		}
+		entries = append(entries, data[:n])
+		data = data[n:]
+	}
+	return entries, nil
+}
+
+// encodeBatchResults encodes one result per input entry as
+// [1-byte ok-flag][4-byte big-endian length][payload]: payload is the value
+// on success (used by cGetBatch) or empty (cSetBatch/cDeleteBatch), and the
+// error message on failure. This keeps partial failure representable within
+// a single UnmanagedVector instead of aborting the whole batch on the first
+// error.
+func encodeBatchResults(values [][]byte, errs []error) []byte {
+	var out []byte
+	for i := range values {
+		ok := byte(1)
+		payload := values[i]
+		if errs[i] != nil {
+			ok = 0
+			payload = []byte(errs[i].Error())
+		}
+		var lenBuf [4]byte
+		binary.BigEndian.PutUint32(lenBuf[:], uint32(len(payload)))
+		out = append(out, ok)
+		out = append(out, lenBuf[:]...)
+		out = append(out, payload...)
+	}
+	return out
+}
+
+// goGetBatch executes a batched Get, preferring the store's native
+// BatchKVStore.GetBatch when available (including its per-key errors) and
+// falling back to one kv.Get call per key otherwise, which never fails.
+func goGetBatch(kv KVStore, keys [][]byte) ([][]byte, []error) {
+	if bkv, ok := kv.(BatchKVStore); ok {
+		return bkv.GetBatch(keys)
+	}
+	values := make([][]byte, len(keys))
+	for i, k := range keys {
+		values[i] = kv.Get(k)
+	}
+	return values, make([]error, len(keys))
+}
+
+// goSetBatch executes a batched Set, preferring the store's native
+// BatchKVStore.SetBatch when available (including its per-key errors) and
+// falling back to one kv.Set call per key otherwise, which never fails.
+func goSetBatch(kv KVStore, keys, values [][]byte) []error {
+	if bkv, ok := kv.(BatchKVStore); ok {
+		return bkv.SetBatch(keys, values)
+	}
+	for i, k := range keys {
+		kv.Set(k, values[i])
+	}
+	return make([]error, len(keys))
+}
+
+// goDeleteBatch executes a batched Delete, preferring the store's native
+// BatchKVStore.DeleteBatch when available (including its per-key errors)
+// and falling back to one kv.Delete call per key otherwise, which never
+// fails.
+func goDeleteBatch(kv KVStore, keys [][]byte) []error {
+	if bkv, ok := kv.(BatchKVStore); ok {
+		return bkv.DeleteBatch(keys)
+	}
+	for _, k := range keys {
+		kv.Delete(k)
+	}
+	return make([]error, len(keys))
+}
@@ -0,0 +1,178 @@
+package api
+
+// These benchmarks compare the Go-side batched vs. per-key KVStore paths.
+// They intentionally stay on the Go side of the boundary rather than driving
+// cGet/cGetBatch directly: constructing a C.U8SliceView requires the layout
+// from bindings.h, which this checkout doesn't have. The cgo transition
+// itself is fixed overhead per call regardless of store; what batching saves
+// is the N-1 extra crossings, which lockedMemKVStore's per-call lock stands
+// in for below.
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+// memKVStore is a trivial in-memory KVStore that does NOT implement
+// BatchKVStore, representing a host store that hasn't opted into native
+// batching; goGetBatch/goSetBatch/goDeleteBatch fall back to looping over
+// its single-key methods for this store.
+type memKVStore struct {
+	data map[string][]byte
+}
+
+func newMemKVStore() *memKVStore {
+	return &memKVStore{data: make(map[string][]byte)}
+}
+
+func (s *memKVStore) Get(key []byte) []byte { return s.data[string(key)] }
+func (s *memKVStore) Set(key, value []byte) { s.data[string(key)] = value }
+func (s *memKVStore) Delete(key []byte)     { delete(s.data, string(key)) }
+func (s *memKVStore) Iterator(start, end []byte) Iterator {
+	panic("not implemented")
+}
+func (s *memKVStore) ReverseIterator(start, end []byte) Iterator {
+	panic("not implemented")
+}
+
+// lockedMemKVStore additionally implements BatchKVStore, taking its mutex
+// once per batch instead of once per key. It stands in for a real store
+// where per-call overhead (gas metering, tree traversal, lock acquisition)
+// dominates a single key lookup, which is exactly the cost batching amortizes.
+type lockedMemKVStore struct {
+	mu   sync.Mutex
+	data map[string][]byte
+}
+
+func newLockedMemKVStore() *lockedMemKVStore {
+	return &lockedMemKVStore{data: make(map[string][]byte)}
+}
+
+func (s *lockedMemKVStore) Get(key []byte) []byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.data[string(key)]
+}
+
+func (s *lockedMemKVStore) Set(key, value []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data[string(key)] = value
+}
+
+func (s *lockedMemKVStore) Delete(key []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.data, string(key))
+}
+
+func (s *lockedMemKVStore) Iterator(start, end []byte) Iterator        { panic("not implemented") }
+func (s *lockedMemKVStore) ReverseIterator(start, end []byte) Iterator { panic("not implemented") }
+
+func (s *lockedMemKVStore) GetBatch(keys [][]byte) ([][]byte, []error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	values := make([][]byte, len(keys))
+	for i, k := range keys {
+		values[i] = s.data[string(k)]
+	}
+	return values, make([]error, len(keys))
+}
+
+func (s *lockedMemKVStore) SetBatch(keys, values [][]byte) []error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, k := range keys {
+		s.data[string(k)] = values[i]
+	}
+	return make([]error, len(keys))
+}
+
+func (s *lockedMemKVStore) DeleteBatch(keys [][]byte) []error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, k := range keys {
+		delete(s.data, string(k))
+	}
+	return make([]error, len(keys))
+}
+
+func benchKeys(n int) [][]byte {
+	keys := make([][]byte, n)
+	for i := range keys {
+		keys[i] = []byte(fmt.Sprintf("key-%d", i))
+	}
+	return keys
+}
+
+// BenchmarkGetSingle measures N individual Get calls against a store with no
+// per-call overhead, as the simplest possible baseline.
+func BenchmarkGetSingle(b *testing.B) {
+	kv := newMemKVStore()
+	keys := benchKeys(64)
+	for _, k := range keys {
+		kv.Set(k, []byte("value"))
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, k := range keys {
+			kv.Get(k)
+		}
+	}
+}
+
+// BenchmarkGetBatchFallback measures goGetBatch against a store that does
+// NOT implement BatchKVStore: it still falls back to N individual Get calls,
+// so this should cost about the same as BenchmarkGetSingle. It exists to
+// show the fallback path isn't free, not to show a speedup.
+func BenchmarkGetBatchFallback(b *testing.B) {
+	kv := newMemKVStore()
+	keys := benchKeys(64)
+	for _, k := range keys {
+		kv.Set(k, []byte("value"))
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		goGetBatch(kv, keys)
+	}
+}
+
+// BenchmarkGetSingleLocked measures N individual Get calls against a store
+// that pays a lock per call, modeling a host where per-call overhead (gas
+// metering, tree traversal) is the dominant cost rather than the lookup
+// itself.
+func BenchmarkGetSingleLocked(b *testing.B) {
+	kv := newLockedMemKVStore()
+	keys := benchKeys(64)
+	for _, k := range keys {
+		kv.Set(k, []byte("value"))
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, k := range keys {
+			kv.Get(k)
+		}
+	}
+}
+
+// BenchmarkGetBatchNative measures goGetBatch against the same
+// lock-per-call store, but now via its native BatchKVStore.GetBatch, which
+// takes the lock once for the whole batch. This is the case the request is
+// actually after: materially fewer per-call round trips for a store where
+// each one carries real overhead.
+func BenchmarkGetBatchNative(b *testing.B) {
+	kv := newLockedMemKVStore()
+	keys := benchKeys(64)
+	for _, k := range keys {
+		kv.Set(k, []byte("value"))
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		goGetBatch(kv, keys)
+	}
+}
@@ -11,25 +11,37 @@ typedef GoResult (*read_db_fn)(db_t *ptr, gas_meter_t *gas_meter, uint64_t *used
 typedef GoResult (*write_db_fn)(db_t *ptr, gas_meter_t *gas_meter, uint64_t *used_gas, U8SliceView key, U8SliceView val, UnmanagedVector *errOut);
 typedef GoResult (*remove_db_fn)(db_t *ptr, gas_meter_t *gas_meter, uint64_t *used_gas, U8SliceView key, UnmanagedVector *errOut);
 typedef GoResult (*scan_db_fn)(db_t *ptr, gas_meter_t *gas_meter, uint64_t *used_gas, U8SliceView start, U8SliceView end, int32_t order, GoIter *out, UnmanagedVector *errOut);
+// batched db (keys/values are concatenated, length-prefixed entries; see api/batch.go)
+typedef GoResult (*read_db_batch_fn)(db_t *ptr, gas_meter_t *gas_meter, uint64_t *used_gas, U8SliceView keys, UnmanagedVector *out, UnmanagedVector *errOut);
+typedef GoResult (*write_db_batch_fn)(db_t *ptr, gas_meter_t *gas_meter, uint64_t *used_gas, U8SliceView keys, U8SliceView values, UnmanagedVector *out, UnmanagedVector *errOut);
+typedef GoResult (*remove_db_batch_fn)(db_t *ptr, gas_meter_t *gas_meter, uint64_t *used_gas, U8SliceView keys, UnmanagedVector *out, UnmanagedVector *errOut);
 // iterator
 typedef GoResult (*next_db_fn)(iterator_t idx, gas_meter_t *gas_meter, uint64_t *used_gas, UnmanagedVector *key, UnmanagedVector *val, UnmanagedVector *errOut);
+typedef GoResult (*close_db_fn)(iterator_t idx, UnmanagedVector *errOut);
 // and api
 typedef GoResult (*humanize_address_fn)(api_t *ptr, U8SliceView src, UnmanagedVector *dest, UnmanagedVector *errOut, uint64_t *used_gas);
 typedef GoResult (*canonicalize_address_fn)(api_t *ptr, U8SliceView src, UnmanagedVector *dest, UnmanagedVector *errOut, uint64_t *used_gas);
 typedef GoResult (*query_external_fn)(querier_t *ptr, uint64_t gas_limit, uint64_t *used_gas, U8SliceView request, UnmanagedVector *result, UnmanagedVector *errOut);
+typedef GoResult (*query_external_batch_fn)(querier_t *ptr, uint64_t gas_limit, uint64_t *used_gas, U8SliceView requests, UnmanagedVector *result, UnmanagedVector *errOut);
 
 // forward declarations (db)
 GoResult cGet_cgo(db_t *ptr, gas_meter_t *gas_meter, uint64_t *used_gas, U8SliceView key, UnmanagedVector *val, UnmanagedVector *errOut);
 GoResult cSet_cgo(db_t *ptr, gas_meter_t *gas_meter, uint64_t *used_gas, U8SliceView key, U8SliceView val, UnmanagedVector *errOut);
 GoResult cDelete_cgo(db_t *ptr, gas_meter_t *gas_meter, uint64_t *used_gas, U8SliceView key, UnmanagedVector *errOut);
 GoResult cScan_cgo(db_t *ptr, gas_meter_t *gas_meter, uint64_t *used_gas, U8SliceView start, U8SliceView end, int32_t order, GoIter *out, UnmanagedVector *errOut);
+// batched db
+GoResult cGetBatch_cgo(db_t *ptr, gas_meter_t *gas_meter, uint64_t *used_gas, U8SliceView keys, UnmanagedVector *out, UnmanagedVector *errOut);
+GoResult cSetBatch_cgo(db_t *ptr, gas_meter_t *gas_meter, uint64_t *used_gas, U8SliceView keys, U8SliceView values, UnmanagedVector *out, UnmanagedVector *errOut);
+GoResult cDeleteBatch_cgo(db_t *ptr, gas_meter_t *gas_meter, uint64_t *used_gas, U8SliceView keys, UnmanagedVector *out, UnmanagedVector *errOut);
 // iterator
 GoResult cNext_cgo(iterator_t *ptr, gas_meter_t *gas_meter, uint64_t *used_gas, UnmanagedVector *key, UnmanagedVector *val, UnmanagedVector *errOut);
+GoResult cCloseIter_cgo(iterator_t *ptr, UnmanagedVector *errOut);
 // api
 GoResult cHumanAddress_cgo(api_t *ptr, U8SliceView src, UnmanagedVector *dest, UnmanagedVector *errOut, uint64_t *used_gas);
 GoResult cCanonicalAddress_cgo(api_t *ptr, U8SliceView src, UnmanagedVector *dest, UnmanagedVector *errOut, uint64_t *used_gas);
 // and querier
 GoResult cQueryExternal_cgo(querier_t *ptr, uint64_t gas_limit, uint64_t *used_gas, U8SliceView request, UnmanagedVector *result, UnmanagedVector *errOut);
+GoResult cQueryExternalBatch_cgo(querier_t *ptr, uint64_t gas_limit, uint64_t *used_gas, U8SliceView requests, UnmanagedVector *result, UnmanagedVector *errOut);
 
 
 */
@@ -41,6 +53,7 @@ import (
 	"log"
 	"reflect"
 	"runtime/debug"
+	"time"
 	"unsafe"
 
 	"github.com/line/wasmvm/types"
@@ -49,6 +62,25 @@ import (
 // Note: we have to include all exports in the same file (at least since they both import bindings.h),
 // or get odd cgo build errors about duplicate definitions
 
+// resultNameFor maps a C.GoResult outcome to the label used on the
+// package's results counter (see Metrics in metrics.go).
+func resultNameFor(ret C.GoResult) resultName {
+	switch ret {
+	case C.GoResult_Ok:
+		return resultOk
+	case C.GoResult_BadArgument:
+		return resultBadArgument
+	case C.GoResult_OutOfGas:
+		return resultOutOfGas
+	case C.GoResult_Panic:
+		return resultPanic
+	case C.GoResult_User:
+		return resultUser
+	default:
+		return resultOther
+	}
+}
+
 func recoverPanic(ret *C.GoResult) {
 	rec := recover()
 	// we don't want to import lfb-sdk
@@ -135,26 +167,35 @@ type Iterator interface {
 }
 
 var db_vtable = C.Db_vtable{
-	read_db:   (C.read_db_fn)(C.cGet_cgo),
-	write_db:  (C.write_db_fn)(C.cSet_cgo),
-	remove_db: (C.remove_db_fn)(C.cDelete_cgo),
-	scan_db:   (C.scan_db_fn)(C.cScan_cgo),
+	read_db:         (C.read_db_fn)(C.cGet_cgo),
+	write_db:        (C.write_db_fn)(C.cSet_cgo),
+	remove_db:       (C.remove_db_fn)(C.cDelete_cgo),
+	scan_db:         (C.scan_db_fn)(C.cScan_cgo),
+	read_db_batch:   (C.read_db_batch_fn)(C.cGetBatch_cgo),
+	write_db_batch:  (C.write_db_batch_fn)(C.cSetBatch_cgo),
+	remove_db_batch: (C.remove_db_batch_fn)(C.cDeleteBatch_cgo),
 }
 
 type DBState struct {
 	Store KVStore
 	// IteratorStackID is used to lookup the proper stack frame for iterators associated with this DB (iterator.go)
 	IteratorStackID uint64
+	// ContractID identifies the contract (typically its code hash) this store
+	// belongs to. It is only used to label metrics (see metrics.go) so that
+	// operators running many contracts can see hot-spot gas and scan cost
+	// per code hash; it has no effect when metrics are disabled.
+	ContractID string
 }
 
 // use this to create C.Db in two steps, so the pointer lives as long as the calling stack
-//   state := buildDBState(kv, counter)
+//   state := buildDBState(kv, counter, contractID)
 //   db := buildDB(&state, &gasMeter)
 //   // then pass db into some FFI function
-func buildDBState(kv KVStore, counter uint64) DBState {
+func buildDBState(kv KVStore, counter uint64, contractID string) DBState {
 	return DBState{
 		Store:           kv,
 		IteratorStackID: counter,
+		ContractID:      contractID,
 	}
 }
 
@@ -169,13 +210,14 @@ func buildDB(state *DBState, gm *GasMeter) C.Db {
 }
 
 var iterator_vtable = C.Iterator_vtable{
-	next_db: (C.next_db_fn)(C.cNext_cgo),
+	next_db:  (C.next_db_fn)(C.cNext_cgo),
+	close_db: (C.close_db_fn)(C.cCloseIter_cgo),
 }
 
 // contract: original pointer/struct referenced must live longer than C.Db struct
 // since this is only used internally, we can verify the code that this is the case
-func buildIterator(dbCounter uint64, it Iterator) C.iterator_t {
-	idx := storeIterator(dbCounter, it)
+func buildIterator(dbCounter uint64, it Iterator, contractID string) C.iterator_t {
+	idx := storeIterator(dbCounter, it, contractID)
 	return C.iterator_t{
 		db_counter:     cu64(dbCounter),
 		iterator_index: cu64(idx),
@@ -184,6 +226,10 @@ func buildIterator(dbCounter uint64, it Iterator) C.iterator_t {
 
 //export cGet
 func cGet(ptr *C.db_t, gasMeter *C.gas_meter_t, usedGas *cu64, key C.U8SliceView, val *C.UnmanagedVector, errOut *C.UnmanagedVector) (ret C.GoResult) {
+	var contractID string
+	var gasUsed Gas
+	start := time.Now()
+	defer func() { metrics.observe("cGet", contractID, start, gasUsed, resultNameFor(ret)) }()
 	defer recoverPanic(&ret)
 
 	if ptr == nil || gasMeter == nil || usedGas == nil || val == nil || errOut == nil {
@@ -195,13 +241,16 @@ func cGet(ptr *C.db_t, gasMeter *C.gas_meter_t, usedGas *cu64, key C.U8SliceView
 	}
 
 	gm := *(*GasMeter)(unsafe.Pointer(gasMeter))
-	kv := *(*KVStore)(unsafe.Pointer(ptr))
+	state := (*DBState)(unsafe.Pointer(ptr))
+	kv := state.Store
+	contractID = state.ContractID
 	k := copyU8Slice(key)
 
 	gasBefore := gm.GasConsumed()
 	v := kv.Get(k)
 	gasAfter := gm.GasConsumed()
-	*usedGas = (cu64)(gasAfter - gasBefore)
+	gasUsed = gasAfter - gasBefore
+	*usedGas = (cu64)(gasUsed)
 
 	// v will equal nil when the key is missing
 	// https://github.com/line/lfb-sdk/blob/786df84b8e0aaa0a1aff79ffbab0541e597ee004/store/types/store.go#L203
@@ -212,6 +261,10 @@ func cGet(ptr *C.db_t, gasMeter *C.gas_meter_t, usedGas *cu64, key C.U8SliceView
 
 //export cSet
 func cSet(ptr *C.db_t, gasMeter *C.gas_meter_t, usedGas *C.uint64_t, key C.U8SliceView, val C.U8SliceView, errOut *C.UnmanagedVector) (ret C.GoResult) {
+	var contractID string
+	var gasUsed Gas
+	start := time.Now()
+	defer func() { metrics.observe("cSet", contractID, start, gasUsed, resultNameFor(ret)) }()
 	defer recoverPanic(&ret)
 
 	if ptr == nil || gasMeter == nil || usedGas == nil || errOut == nil {
@@ -223,20 +276,27 @@ func cSet(ptr *C.db_t, gasMeter *C.gas_meter_t, usedGas *C.uint64_t, key C.U8Sli
 	}
 
 	gm := *(*GasMeter)(unsafe.Pointer(gasMeter))
-	kv := *(*KVStore)(unsafe.Pointer(ptr))
+	state := (*DBState)(unsafe.Pointer(ptr))
+	kv := state.Store
+	contractID = state.ContractID
 	k := copyU8Slice(key)
 	v := copyU8Slice(val)
 
 	gasBefore := gm.GasConsumed()
 	kv.Set(k, v)
 	gasAfter := gm.GasConsumed()
-	*usedGas = (C.uint64_t)(gasAfter - gasBefore)
+	gasUsed = gasAfter - gasBefore
+	*usedGas = (C.uint64_t)(gasUsed)
 
 	return C.GoResult_Ok
 }
 
 //export cDelete
 func cDelete(ptr *C.db_t, gasMeter *C.gas_meter_t, usedGas *C.uint64_t, key C.U8SliceView, errOut *C.UnmanagedVector) (ret C.GoResult) {
+	var contractID string
+	var gasUsed Gas
+	start := time.Now()
+	defer func() { metrics.observe("cDelete", contractID, start, gasUsed, resultNameFor(ret)) }()
 	defer recoverPanic(&ret)
 
 	if ptr == nil || gasMeter == nil || usedGas == nil || errOut == nil {
@@ -248,19 +308,26 @@ func cDelete(ptr *C.db_t, gasMeter *C.gas_meter_t, usedGas *C.uint64_t, key C.U8
 	}
 
 	gm := *(*GasMeter)(unsafe.Pointer(gasMeter))
-	kv := *(*KVStore)(unsafe.Pointer(ptr))
+	state := (*DBState)(unsafe.Pointer(ptr))
+	kv := state.Store
+	contractID = state.ContractID
 	k := copyU8Slice(key)
 
 	gasBefore := gm.GasConsumed()
 	kv.Delete(k)
 	gasAfter := gm.GasConsumed()
-	*usedGas = (C.uint64_t)(gasAfter - gasBefore)
+	gasUsed = gasAfter - gasBefore
+	*usedGas = (C.uint64_t)(gasUsed)
 
 	return C.GoResult_Ok
 }
 
 //export cScan
 func cScan(ptr *C.db_t, gasMeter *C.gas_meter_t, usedGas *C.uint64_t, start C.U8SliceView, end C.U8SliceView, order ci32, out *C.GoIter, errOut *C.UnmanagedVector) (ret C.GoResult) {
+	var contractID string
+	var gasUsed Gas
+	callStart := time.Now()
+	defer func() { metrics.observe("cScan", contractID, callStart, gasUsed, resultNameFor(ret)) }()
 	defer recoverPanic(&ret)
 
 	if ptr == nil || gasMeter == nil || usedGas == nil || out == nil || errOut == nil {
@@ -274,6 +341,7 @@ func cScan(ptr *C.db_t, gasMeter *C.gas_meter_t, usedGas *C.uint64_t, start C.U8
 	gm := *(*GasMeter)(unsafe.Pointer(gasMeter))
 	state := (*DBState)(unsafe.Pointer(ptr))
 	kv := state.Store
+	contractID = state.ContractID
 	s := copyU8Slice(start)
 	e := copyU8Slice(end)
 
@@ -288,13 +356,134 @@ func cScan(ptr *C.db_t, gasMeter *C.gas_meter_t, usedGas *C.uint64_t, start C.U8
 		return C.GoResult_BadArgument
 	}
 	gasAfter := gm.GasConsumed()
-	*usedGas = (C.uint64_t)(gasAfter - gasBefore)
+	gasUsed = gasAfter - gasBefore
+	*usedGas = (C.uint64_t)(gasUsed)
 
-	out.state = buildIterator(state.IteratorStackID, iter)
+	out.state = buildIterator(state.IteratorStackID, iter, state.ContractID)
 	out.vtable = iterator_vtable
 	return C.GoResult_Ok
 }
 
+//export cGetBatch
+func cGetBatch(ptr *C.db_t, gasMeter *C.gas_meter_t, usedGas *C.uint64_t, keys C.U8SliceView, out *C.UnmanagedVector, errOut *C.UnmanagedVector) (ret C.GoResult) {
+	var contractID string
+	var gasUsed Gas
+	start := time.Now()
+	defer func() { metrics.observe("cGetBatch", contractID, start, gasUsed, resultNameFor(ret)) }()
+	defer recoverPanic(&ret)
+
+	if ptr == nil || gasMeter == nil || usedGas == nil || out == nil || errOut == nil {
+		// we received an invalid pointer
+		return C.GoResult_BadArgument
+	}
+	if !(*out).is_none || !(*errOut).is_none {
+		panic("Got a non-none UnmanagedVector we're about to override. This is a bug because someone has to drop the old one.")
+	}
+
+	state := (*DBState)(unsafe.Pointer(ptr))
+	kv := state.Store
+	contractID = state.ContractID
+
+	keyEntries, err := splitBatchEntries(copyU8Slice(keys))
+	if err != nil {
+		*errOut = newUnmanagedVector([]byte(err.Error()))
+		return C.GoResult_BadArgument
+	}
+
+	gm := *(*GasMeter)(unsafe.Pointer(gasMeter))
+	gasBefore := gm.GasConsumed()
+	values, errs := goGetBatch(kv, keyEntries)
+	gasAfter := gm.GasConsumed()
+	gasUsed = gasAfter - gasBefore
+	*usedGas = (C.uint64_t)(gasUsed)
+
+	*out = newUnmanagedVector(encodeBatchResults(values, errs))
+	return C.GoResult_Ok
+}
+
+//export cSetBatch
+func cSetBatch(ptr *C.db_t, gasMeter *C.gas_meter_t, usedGas *C.uint64_t, keys C.U8SliceView, values C.U8SliceView, out *C.UnmanagedVector, errOut *C.UnmanagedVector) (ret C.GoResult) {
+	var contractID string
+	var gasUsed Gas
+	start := time.Now()
+	defer func() { metrics.observe("cSetBatch", contractID, start, gasUsed, resultNameFor(ret)) }()
+	defer recoverPanic(&ret)
+
+	if ptr == nil || gasMeter == nil || usedGas == nil || out == nil || errOut == nil {
+		// we received an invalid pointer
+		return C.GoResult_BadArgument
+	}
+	if !(*out).is_none || !(*errOut).is_none {
+		panic("Got a non-none UnmanagedVector we're about to override. This is a bug because someone has to drop the old one.")
+	}
+
+	state := (*DBState)(unsafe.Pointer(ptr))
+	kv := state.Store
+	contractID = state.ContractID
+
+	keyEntries, err := splitBatchEntries(copyU8Slice(keys))
+	if err != nil {
+		*errOut = newUnmanagedVector([]byte(err.Error()))
+		return C.GoResult_BadArgument
+	}
+	valueEntries, err := splitBatchEntries(copyU8Slice(values))
+	if err != nil {
+		*errOut = newUnmanagedVector([]byte(err.Error()))
+		return C.GoResult_BadArgument
+	}
+	if len(keyEntries) != len(valueEntries) {
+		*errOut = newUnmanagedVector([]byte(fmt.Sprintf("batch key/value count mismatch: %d keys, %d values", len(keyEntries), len(valueEntries))))
+		return C.GoResult_BadArgument
+	}
+
+	gm := *(*GasMeter)(unsafe.Pointer(gasMeter))
+	gasBefore := gm.GasConsumed()
+	errs := goSetBatch(kv, keyEntries, valueEntries)
+	gasAfter := gm.GasConsumed()
+	gasUsed = gasAfter - gasBefore
+	*usedGas = (C.uint64_t)(gasUsed)
+
+	*out = newUnmanagedVector(encodeBatchResults(make([][]byte, len(keyEntries)), errs))
+	return C.GoResult_Ok
+}
+
+//export cDeleteBatch
+func cDeleteBatch(ptr *C.db_t, gasMeter *C.gas_meter_t, usedGas *C.uint64_t, keys C.U8SliceView, out *C.UnmanagedVector, errOut *C.UnmanagedVector) (ret C.GoResult) {
+	var contractID string
+	var gasUsed Gas
+	start := time.Now()
+	defer func() { metrics.observe("cDeleteBatch", contractID, start, gasUsed, resultNameFor(ret)) }()
+	defer recoverPanic(&ret)
+
+	if ptr == nil || gasMeter == nil || usedGas == nil || out == nil || errOut == nil {
+		// we received an invalid pointer
+		return C.GoResult_BadArgument
+	}
+	if !(*out).is_none || !(*errOut).is_none {
+		panic("Got a non-none UnmanagedVector we're about to override. This is a bug because someone has to drop the old one.")
+	}
+
+	state := (*DBState)(unsafe.Pointer(ptr))
+	kv := state.Store
+	contractID = state.ContractID
+
+	keyEntries, err := splitBatchEntries(copyU8Slice(keys))
+	if err != nil {
+		*errOut = newUnmanagedVector([]byte(err.Error()))
+		return C.GoResult_BadArgument
+	}
+
+	gm := *(*GasMeter)(unsafe.Pointer(gasMeter))
+	gasBefore := gm.GasConsumed()
+	errs := goDeleteBatch(kv, keyEntries)
+	gasAfter := gm.GasConsumed()
+	gasUsed = gasAfter - gasBefore
+	*usedGas = (C.uint64_t)(gasUsed)
+
+	*out = newUnmanagedVector(encodeBatchResults(make([][]byte, len(keyEntries)), errs))
+	return C.GoResult_Ok
+}
+
 //export cNext
 func cNext(ref C.iterator_t, gasMeter *C.gas_meter_t, usedGas *C.uint64_t, key *C.UnmanagedVector, val *C.UnmanagedVector, errOut *C.UnmanagedVector) (ret C.GoResult) {
 	// typical usage of iterator
@@ -303,6 +492,22 @@ func cNext(ref C.iterator_t, gasMeter *C.gas_meter_t, usedGas *C.uint64_t, key *
 	// 		...
 	// 	}
 
+	var contractID string
+	var gasUsed Gas
+	start := time.Now()
+	defer func() { metrics.observe("cNext", contractID, start, gasUsed, resultNameFor(ret)) }()
+	// If cNext panics (e.g. a buggy Iterator implementation) or recovers an
+	// out-of-gas panic (recoverPanic maps that to GoResult_OutOfGas, not
+	// GoResult_Panic), the Rust side never gets a clean return through which
+	// to call cCloseIter, so close the iterator here for any non-Ok result
+	// instead of waiting for it to be dropped.
+	defer func() {
+		if ret != C.GoResult_Ok {
+			if it, ok := removeIterator(uint64(ref.db_counter), uint64(ref.iterator_index)); ok {
+				_ = it.Close()
+			}
+		}
+	}()
 	defer recoverPanic(&ret)
 	if ref.db_counter == 0 || gasMeter == nil || usedGas == nil || key == nil || val == nil || errOut == nil {
 		// we received an invalid pointer
@@ -313,7 +518,8 @@ func cNext(ref C.iterator_t, gasMeter *C.gas_meter_t, usedGas *C.uint64_t, key *
 	}
 
 	gm := *(*GasMeter)(unsafe.Pointer(gasMeter))
-	iter := retrieveIterator(uint64(ref.db_counter), uint64(ref.iterator_index))
+	iter, contractIDForIter := retrieveIterator(uint64(ref.db_counter), uint64(ref.iterator_index))
+	contractID = contractIDForIter
 	if !iter.Valid() {
 		// end of iterator, return as no-op, nil key is considered end
 		return C.GoResult_Ok
@@ -326,13 +532,45 @@ func cNext(ref C.iterator_t, gasMeter *C.gas_meter_t, usedGas *C.uint64_t, key *
 	// check iter.Error() ????
 	iter.Next()
 	gasAfter := gm.GasConsumed()
-	*usedGas = (C.uint64_t)(gasAfter - gasBefore)
+	gasUsed = gasAfter - gasBefore
+	*usedGas = (C.uint64_t)(gasUsed)
 
 	*key = newUnmanagedVector(k)
 	*val = newUnmanagedVector(v)
 	return C.GoResult_Ok
 }
 
+//export cCloseIter
+func cCloseIter(ref C.iterator_t, errOut *C.UnmanagedVector) (ret C.GoResult) {
+	// called once Rust drops its GoIter, whether the iterator was exhausted
+	// by cNext or abandoned early (e.g. the contract stopped reading partway
+	// through a range). Iterator.Close() documents that the caller must
+	// close it, and lfb-sdk store iterators hold onto real resources
+	// (LevelDB snapshots, tree cursors), so this must run eagerly rather
+	// than waiting for the whole call frame to unwind.
+	defer recoverPanic(&ret)
+
+	if ref.db_counter == 0 || errOut == nil {
+		// we received an invalid pointer
+		return C.GoResult_BadArgument
+	}
+	if !(*errOut).is_none {
+		panic("Got a non-none UnmanagedVector we're about to override. This is a bug because someone has to drop the old one.")
+	}
+
+	it, ok := removeIterator(uint64(ref.db_counter), uint64(ref.iterator_index))
+	if !ok {
+		// already closed (e.g. by the panic-recovery path in cNext); closing
+		// twice is a no-op, not an error
+		return C.GoResult_Ok
+	}
+	if err := it.Close(); err != nil {
+		*errOut = newUnmanagedVector([]byte(err.Error()))
+		return C.GoResult_Other
+	}
+	return C.GoResult_Ok
+}
+
 /***** GoAPI *******/
 
 type HumanizeAddress func([]byte) (string, uint64, error)
@@ -341,6 +579,9 @@ type CanonicalizeAddress func(string) ([]byte, uint64, error)
 type GoAPI struct {
 	HumanAddress     HumanizeAddress
 	CanonicalAddress CanonicalizeAddress
+	// ContractID labels the metrics recorded for this API's callbacks (see
+	// metrics.go); it has no effect when metrics are disabled.
+	ContractID string
 }
 
 var api_vtable = C.GoApi_vtable{
@@ -359,6 +600,10 @@ func buildAPI(api *GoAPI) C.GoApi {
 
 //export cHumanAddress
 func cHumanAddress(ptr *C.api_t, src C.U8SliceView, dest *C.UnmanagedVector, errOut *C.UnmanagedVector, used_gas *cu64) (ret C.GoResult) {
+	var contractID string
+	var gasUsed Gas
+	start := time.Now()
+	defer func() { metrics.observe("cHumanAddress", contractID, start, gasUsed, resultNameFor(ret)) }()
 	defer recoverPanic(&ret)
 
 	if dest == nil || errOut == nil {
@@ -369,9 +614,11 @@ func cHumanAddress(ptr *C.api_t, src C.U8SliceView, dest *C.UnmanagedVector, err
 	}
 
 	api := (*GoAPI)(unsafe.Pointer(ptr))
+	contractID = api.ContractID
 	s := copyU8Slice(src)
 
 	h, cost, err := api.HumanAddress(s)
+	gasUsed = Gas(cost)
 	*used_gas = cu64(cost)
 	if err != nil {
 		// store the actual error message in the return buffer
@@ -387,6 +634,10 @@ func cHumanAddress(ptr *C.api_t, src C.U8SliceView, dest *C.UnmanagedVector, err
 
 //export cCanonicalAddress
 func cCanonicalAddress(ptr *C.api_t, src C.U8SliceView, dest *C.UnmanagedVector, errOut *C.UnmanagedVector, used_gas *cu64) (ret C.GoResult) {
+	var contractID string
+	var gasUsed Gas
+	start := time.Now()
+	defer func() { metrics.observe("cCanonicalAddress", contractID, start, gasUsed, resultNameFor(ret)) }()
 	defer recoverPanic(&ret)
 
 	if dest == nil || errOut == nil {
@@ -397,8 +648,10 @@ func cCanonicalAddress(ptr *C.api_t, src C.U8SliceView, dest *C.UnmanagedVector,
 	}
 
 	api := (*GoAPI)(unsafe.Pointer(ptr))
+	contractID = api.ContractID
 	s := string(copyU8Slice(src))
 	c, cost, err := api.CanonicalAddress(s)
+	gasUsed = Gas(cost)
 	*used_gas = cu64(cost)
 	if err != nil {
 		// store the actual error message in the return buffer
@@ -414,21 +667,41 @@ func cCanonicalAddress(ptr *C.api_t, src C.U8SliceView, dest *C.UnmanagedVector,
 
 /****** Go Querier ********/
 
+// Querier copies a subset of the interface from lfb-sdk/types.
+// Defined in https://github.com/line/lfb-sdk/blob/main/baseapp/abci.go
+type Querier interface {
+	// GasConsumed returns the gas consumed from the gas meter this querier was built with.
+	GasConsumed() Gas
+}
+
+// QuerierState wraps a Querier together with the contract identifier it is
+// serving, so cQueryExternal can label metrics per contract without changing
+// the Querier interface itself.
+type QuerierState struct {
+	Querier    Querier
+	ContractID string
+}
+
 var querier_vtable = C.Querier_vtable{
-	query_external: (C.query_external_fn)(C.cQueryExternal_cgo),
+	query_external:       (C.query_external_fn)(C.cQueryExternal_cgo),
+	query_external_batch: (C.query_external_batch_fn)(C.cQueryExternalBatch_cgo),
 }
 
 // contract: original pointer/struct referenced must live longer than C.GoQuerier struct
 // since this is only used internally, we can verify the code that this is the case
-func buildQuerier(q *Querier) C.GoQuerier {
+func buildQuerier(state *QuerierState) C.GoQuerier {
 	return C.GoQuerier{
-		state:  (*C.querier_t)(unsafe.Pointer(q)),
+		state:  (*C.querier_t)(unsafe.Pointer(state)),
 		vtable: querier_vtable,
 	}
 }
 
 //export cQueryExternal
 func cQueryExternal(ptr *C.querier_t, gasLimit C.uint64_t, usedGas *C.uint64_t, request C.U8SliceView, result *C.UnmanagedVector, errOut *C.UnmanagedVector) (ret C.GoResult) {
+	var contractID string
+	var gasUsed Gas
+	start := time.Now()
+	defer func() { metrics.observe("cQueryExternal", contractID, start, gasUsed, resultNameFor(ret)) }()
 	defer recoverPanic(&ret)
 
 	if ptr == nil || usedGas == nil || result == nil || errOut == nil {
@@ -440,13 +713,16 @@ func cQueryExternal(ptr *C.querier_t, gasLimit C.uint64_t, usedGas *C.uint64_t,
 	}
 
 	// query the data
-	querier := *(*Querier)(unsafe.Pointer(ptr))
+	state := (*QuerierState)(unsafe.Pointer(ptr))
+	querier := state.Querier
+	contractID = state.ContractID
 	req := copyU8Slice(request)
 
 	gasBefore := querier.GasConsumed()
 	res := types.RustQuery(querier, req, uint64(gasLimit))
 	gasAfter := querier.GasConsumed()
-	*usedGas = (C.uint64_t)(gasAfter - gasBefore)
+	gasUsed = gasAfter - gasBefore
+	*usedGas = (C.uint64_t)(gasUsed)
 
 	// serialize the response
 	bz, err := json.Marshal(res)
@@ -457,3 +733,45 @@ func cQueryExternal(ptr *C.querier_t, gasLimit C.uint64_t, usedGas *C.uint64_t,
 	*result = newUnmanagedVector(bz)
 	return C.GoResult_Ok
 }
+
+//export cQueryExternalBatch
+func cQueryExternalBatch(ptr *C.querier_t, gasLimit C.uint64_t, usedGas *C.uint64_t, requests C.U8SliceView, result *C.UnmanagedVector, errOut *C.UnmanagedVector) (ret C.GoResult) {
+	var contractID string
+	var gasUsed Gas
+	start := time.Now()
+	defer func() { metrics.observe("cQueryExternalBatch", contractID, start, gasUsed, resultNameFor(ret)) }()
+	defer recoverPanic(&ret)
+
+	if ptr == nil || usedGas == nil || result == nil || errOut == nil {
+		// we received an invalid pointer
+		return C.GoResult_BadArgument
+	}
+	if !(*result).is_none || !(*errOut).is_none {
+		panic("Got a non-none UnmanagedVector we're about to override. This is a bug because someone has to drop the old one.")
+	}
+
+	state := (*QuerierState)(unsafe.Pointer(ptr))
+	querier := state.Querier
+	contractID = state.ContractID
+
+	var reqs []json.RawMessage
+	if err := json.Unmarshal(copyU8Slice(requests), &reqs); err != nil {
+		*errOut = newUnmanagedVector([]byte(err.Error()))
+		return C.GoResult_BadArgument
+	}
+
+	gasBefore := querier.GasConsumed()
+	results := runQueryBatch(querier, reqs, uint64(gasLimit))
+	gasAfter := querier.GasConsumed()
+	gasUsed = gasAfter - gasBefore
+	*usedGas = (C.uint64_t)(gasUsed)
+
+	// serialize the response
+	bz, err := json.Marshal(results)
+	if err != nil {
+		*errOut = newUnmanagedVector([]byte(err.Error()))
+		return C.GoResult_Other
+	}
+	*result = newUnmanagedVector(bz)
+	return C.GoResult_Ok
+}
@@ -0,0 +1,89 @@
+package api
+
+import (
+	"fmt"
+	"sync"
+)
+
+// iteratorEntry pairs a stored Iterator with the contract identifier of the
+// DBState that opened it (DBState.ContractID), so per-step metrics recorded
+// while walking the iterator (cNext) can be labeled the same way cScan
+// labels iterator creation.
+type iteratorEntry struct {
+	iterator   Iterator
+	contractID string
+}
+
+// iteratorFrame holds all iterators opened against one DBState instance
+// (identified by DBState.IteratorStackID), keyed by the index handed back to
+// Rust in C.iterator_t.iterator_index.
+type iteratorFrame struct {
+	entries   map[uint64]iteratorEntry
+	nextIndex uint64
+}
+
+var (
+	iteratorFramesMutex sync.Mutex
+	iteratorFrames      = make(map[uint64]*iteratorFrame)
+)
+
+// storeIterator registers it under dbCounter's frame, tagged with
+// contractID, and returns the index Rust should use to refer to it in
+// subsequent cNext/cCloseIter calls.
+func storeIterator(dbCounter uint64, it Iterator, contractID string) uint64 {
+	iteratorFramesMutex.Lock()
+	defer iteratorFramesMutex.Unlock()
+
+	frame, ok := iteratorFrames[dbCounter]
+	if !ok {
+		frame = &iteratorFrame{entries: make(map[uint64]iteratorEntry)}
+		iteratorFrames[dbCounter] = frame
+	}
+	frame.nextIndex++
+	idx := frame.nextIndex
+	frame.entries[idx] = iteratorEntry{iterator: it, contractID: contractID}
+	return idx
+}
+
+// retrieveIterator looks up a previously stored iterator and the contract
+// identifier it was stored with, without removing it. It panics if the
+// iterator is unknown, since Rust is only ever handed indices that came from
+// storeIterator and must not reference one outside its lifetime.
+func retrieveIterator(dbCounter, index uint64) (Iterator, string) {
+	iteratorFramesMutex.Lock()
+	defer iteratorFramesMutex.Unlock()
+
+	frame, ok := iteratorFrames[dbCounter]
+	if !ok {
+		panic(fmt.Sprintf("unknown db_counter %d when looking up iterator", dbCounter))
+	}
+	entry, ok := frame.entries[index]
+	if !ok {
+		panic(fmt.Sprintf("unknown iterator_index %d in db_counter %d", index, dbCounter))
+	}
+	return entry.iterator, entry.contractID
+}
+
+// removeIterator releases the iterator at (dbCounter, index) from the
+// registry, if present, and returns it so the caller can Close() it. Entries
+// are released eagerly, as soon as the iterator itself is done with
+// (cCloseIter, or a panic inside cNext), rather than only when the whole
+// frame for dbCounter is torn down.
+func removeIterator(dbCounter, index uint64) (Iterator, bool) {
+	iteratorFramesMutex.Lock()
+	defer iteratorFramesMutex.Unlock()
+
+	frame, ok := iteratorFrames[dbCounter]
+	if !ok {
+		return nil, false
+	}
+	entry, ok := frame.entries[index]
+	if !ok {
+		return nil, false
+	}
+	delete(frame.entries, index)
+	if len(frame.entries) == 0 {
+		delete(iteratorFrames, dbCounter)
+	}
+	return entry.iterator, true
+}
@@ -0,0 +1,184 @@
+package api
+
+/*
+#include "bindings.h"
+*/
+import "C"
+
+import (
+	"testing"
+	"unsafe"
+)
+
+// ErrorOutOfGas stands in for the panic type lfb-sdk's gas meter throws
+// (https://github.com/line/lfb-sdk/blob/main/store/types/gas.go). recoverPanic
+// detects it by type name via reflection rather than importing lfb-sdk, so
+// this local type (same name, any package) is enough to exercise that path.
+type ErrorOutOfGas struct {
+	Descriptor string
+}
+
+// countingIterator wraps a slice of key/value pairs and counts how many
+// times Close() is called, so tests can assert it happens exactly once.
+type countingIterator struct {
+	pairs      [][2][]byte
+	pos        int
+	closed     int
+	nextPanics bool
+	panicValue interface{}
+}
+
+func (it *countingIterator) Valid() bool { return it.pos < len(it.pairs) }
+
+func (it *countingIterator) Next() {
+	if it.nextPanics {
+		if it.panicValue != nil {
+			panic(it.panicValue)
+		}
+		panic("boom")
+	}
+	it.pos++
+}
+
+func (it *countingIterator) Key() []byte   { return it.pairs[it.pos][0] }
+func (it *countingIterator) Value() []byte { return it.pairs[it.pos][1] }
+func (it *countingIterator) Error() error  { return nil }
+
+func (it *countingIterator) Close() error {
+	it.closed++
+	return nil
+}
+
+type zeroGasMeter struct{}
+
+func (zeroGasMeter) GasConsumed() Gas { return 0 }
+
+func refFor(dbCounter, index uint64) C.iterator_t {
+	return C.iterator_t{
+		db_counter:     cu64(dbCounter),
+		iterator_index: cu64(index),
+	}
+}
+
+// noneVector returns an empty (none) UnmanagedVector, the only state the
+// callbacks accept for their output arguments.
+func noneVector() C.UnmanagedVector {
+	return C.UnmanagedVector{is_none: true}
+}
+
+func TestCloseIterExhausted(t *testing.T) {
+	it := &countingIterator{pairs: [][2][]byte{{[]byte("a"), []byte("1")}}}
+	idx := storeIterator(42, it, "contract-a")
+
+	var gasMeter GasMeter = zeroGasMeter{}
+	ref := refFor(42, idx)
+
+	// drain the iterator the way Rust does, via repeated cNext calls
+	for {
+		key, val, errOut := noneVector(), noneVector(), noneVector()
+		var usedGas C.uint64_t
+		ret := cNext(ref, (*C.gas_meter_t)(unsafe.Pointer(&gasMeter)), &usedGas, &key, &val, &errOut)
+		if ret != C.GoResult_Ok {
+			t.Fatalf("cNext returned %v", ret)
+		}
+		if key.is_none {
+			break
+		}
+	}
+
+	errOut := noneVector()
+	if ret := cCloseIter(ref, &errOut); ret != C.GoResult_Ok {
+		t.Fatalf("cCloseIter returned %v", ret)
+	}
+	if it.closed != 1 {
+		t.Fatalf("expected Close() exactly once, got %d", it.closed)
+	}
+
+	// closing again must be a harmless no-op, not a second Close() call
+	if ret := cCloseIter(ref, &errOut); ret != C.GoResult_Ok {
+		t.Fatalf("second cCloseIter returned %v", ret)
+	}
+	if it.closed != 1 {
+		t.Fatalf("expected Close() still called exactly once, got %d", it.closed)
+	}
+}
+
+func TestCloseIterAbandoned(t *testing.T) {
+	it := &countingIterator{pairs: [][2][]byte{{[]byte("a"), []byte("1")}, {[]byte("b"), []byte("2")}}}
+	idx := storeIterator(43, it, "contract-b")
+	ref := refFor(43, idx)
+
+	// abandon the iterator without ever calling cNext
+	errOut := noneVector()
+	if ret := cCloseIter(ref, &errOut); ret != C.GoResult_Ok {
+		t.Fatalf("cCloseIter returned %v", ret)
+	}
+	if it.closed != 1 {
+		t.Fatalf("expected Close() exactly once, got %d", it.closed)
+	}
+	if _, ok := retrieveIteratorSafe(43, idx); ok {
+		t.Fatalf("expected iterator to be removed from the registry")
+	}
+}
+
+func TestCNextPanicClosesIterator(t *testing.T) {
+	it := &countingIterator{
+		pairs:      [][2][]byte{{[]byte("a"), []byte("1")}},
+		nextPanics: true,
+	}
+	idx := storeIterator(44, it, "contract-c")
+	ref := refFor(44, idx)
+
+	var gasMeter GasMeter = zeroGasMeter{}
+	key, val, errOut := noneVector(), noneVector(), noneVector()
+	var usedGas C.uint64_t
+
+	// cNext calls iter.Next() after reading Key()/Value(), which panics here;
+	// recoverPanic must turn that into GoResult_Panic and still close the iterator
+	ret := cNext(ref, (*C.gas_meter_t)(unsafe.Pointer(&gasMeter)), &usedGas, &key, &val, &errOut)
+	if ret != C.GoResult_Panic {
+		t.Fatalf("expected GoResult_Panic, got %v", ret)
+	}
+	if it.closed != 1 {
+		t.Fatalf("expected Close() exactly once after panic, got %d", it.closed)
+	}
+}
+
+func TestCNextOutOfGasPanicClosesIterator(t *testing.T) {
+	it := &countingIterator{
+		pairs:      [][2][]byte{{[]byte("a"), []byte("1")}},
+		nextPanics: true,
+		panicValue: ErrorOutOfGas{Descriptor: "out of gas"},
+	}
+	idx := storeIterator(45, it, "contract-d")
+	ref := refFor(45, idx)
+
+	var gasMeter GasMeter = zeroGasMeter{}
+	key, val, errOut := noneVector(), noneVector(), noneVector()
+	var usedGas C.uint64_t
+
+	// recoverPanic maps an ErrorOutOfGas panic to GoResult_OutOfGas, not
+	// GoResult_Panic, so the close-on-panic defer must fire on that result
+	// too, not only on GoResult_Panic.
+	ret := cNext(ref, (*C.gas_meter_t)(unsafe.Pointer(&gasMeter)), &usedGas, &key, &val, &errOut)
+	if ret != C.GoResult_OutOfGas {
+		t.Fatalf("expected GoResult_OutOfGas, got %v", ret)
+	}
+	if it.closed != 1 {
+		t.Fatalf("expected Close() exactly once after out-of-gas panic, got %d", it.closed)
+	}
+}
+
+// retrieveIteratorSafe is a test-only helper that reports whether an
+// iterator is still registered, instead of panicking like retrieveIterator.
+func retrieveIteratorSafe(dbCounter, index uint64) (Iterator, bool) {
+	iteratorFramesMutex.Lock()
+	defer iteratorFramesMutex.Unlock()
+
+	frame, ok := iteratorFrames[dbCounter]
+	if !ok {
+		return nil, false
+	}
+	entry, ok := frame.entries[index]
+	return entry.iterator, ok
+}
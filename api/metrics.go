@@ -0,0 +1,90 @@
+package api
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Metrics holds the optional Prometheus instrumentation for the FFI callbacks
+// in this package. It is nil by default, so nothing is recorded (observe
+// returns at its nil check) unless an operator opts in via SetMetrics.
+type Metrics struct {
+	// callDuration tracks wall-clock latency of each callback, labeled by
+	// callback name and contract. Summaries are backed by perks/quantile,
+	// matching the rest of the Cosmos/lfb-sdk ecosystem's metrics.
+	callDuration *prometheus.SummaryVec
+	// gasUsed tracks the gas consumed (gasAfter - gasBefore) per call.
+	gasUsed *prometheus.SummaryVec
+	// results counts outcomes by GoResult code, so operators can spot
+	// panics, out-of-gas and user errors per callback/contract.
+	results *prometheus.CounterVec
+}
+
+// NewMetrics builds a Metrics instance and registers its collectors with reg.
+// Pass this to SetMetrics to enable instrumentation; reg is typically a
+// *prometheus.Registry owned by the embedding application.
+func NewMetrics(reg prometheus.Registerer) *Metrics {
+	m := &Metrics{
+		callDuration: prometheus.NewSummaryVec(prometheus.SummaryOpts{
+			Namespace:  "wasmvm",
+			Subsystem:  "callback",
+			Name:       "duration_seconds",
+			Help:       "Wall-clock latency of FFI callbacks exported to the Rust side.",
+			Objectives: map[float64]float64{0.5: 0.05, 0.9: 0.01, 0.99: 0.001},
+		}, []string{"callback", "contract"}),
+		gasUsed: prometheus.NewSummaryVec(prometheus.SummaryOpts{
+			Namespace:  "wasmvm",
+			Subsystem:  "callback",
+			Name:       "gas_used",
+			Help:       "Gas consumed by a single invocation of an FFI callback.",
+			Objectives: map[float64]float64{0.5: 0.05, 0.9: 0.01, 0.99: 0.001},
+		}, []string{"callback", "contract"}),
+		results: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "wasmvm",
+			Subsystem: "callback",
+			Name:      "results_total",
+			Help:      "Count of FFI callback invocations by GoResult outcome.",
+		}, []string{"callback", "contract", "result"}),
+	}
+	reg.MustRegister(m.callDuration, m.gasUsed, m.results)
+	return m
+}
+
+// metrics is the package-level instrumentation hook. It is left nil until
+// SetMetrics is called, so the default build records no metrics. Callbacks
+// still take a start time and call observe on every invocation.
+var metrics *Metrics
+
+// SetMetrics installs m as the package-level metrics sink. Passing nil
+// disables instrumentation again. This must be called before any contract
+// calls are made, since callbacks read metrics without synchronization.
+func SetMetrics(m *Metrics) {
+	metrics = m
+}
+
+// observe records one callback invocation. contractID may be empty when the
+// caller has not threaded one through (e.g. older integrations); start is the
+// time the callback began, and gasUsed is gasAfter-gasBefore as already
+// computed by the caller.
+func (m *Metrics) observe(callback, contractID string, start time.Time, gasUsed uint64, result resultName) {
+	if m == nil {
+		return
+	}
+	m.callDuration.WithLabelValues(callback, contractID).Observe(time.Since(start).Seconds())
+	m.gasUsed.WithLabelValues(callback, contractID).Observe(float64(gasUsed))
+	m.results.WithLabelValues(callback, contractID, string(result)).Inc()
+}
+
+// resultName is the label used on the results counter for a C.GoResult
+// outcome (see resultNameFor in callbacks.go).
+type resultName string
+
+const (
+	resultOk          resultName = "ok"
+	resultBadArgument resultName = "bad_argument"
+	resultOutOfGas    resultName = "out_of_gas"
+	resultPanic       resultName = "panic"
+	resultUser        resultName = "user"
+	resultOther       resultName = "other"
+)
@@ -0,0 +1,59 @@
+package api
+
+import (
+	"encoding/json"
+
+	"github.com/line/wasmvm/types"
+)
+
+// runQueryBatch executes reqs against querier one at a time, metering gas
+// against the shared gasLimit budget: once it's exhausted, remaining
+// sub-queries are still run, but with a zero gas limit, so types.RustQuery
+// reports the exhaustion for that sub-query itself rather than runQueryBatch
+// inventing its own error shape. Every element of the returned slice is
+// therefore exactly the JSON a single cQueryExternal call would have
+// produced for the same request — no batch-specific wrapper — so a Rust
+// decoder can treat the response uniformly as an array of QuerierResult.
+// Results are returned in request order.
+//
+// Sub-queries are NOT run concurrently, despite cQueryExternalBatch's name.
+// A Querier's gas meter (and the querier itself, in general) is not safe for
+// concurrent use — a real Cosmos/lfb-sdk gas meter is a plain mutable struct
+// that types.RustQuery writes to as it runs, and cloning an independent,
+// gas-reconciling querier per worker isn't something any standard SDK
+// querier supports. Running two RustQuery calls against the same querier
+// concurrently is a data race and would make gas accounting nondeterministic
+// across validators, which is consensus-fatal in a gas-metered VM. So
+// cQueryExternalBatch only batches the wire format: it lets a contract ask
+// for N sub-queries in one FFI round-trip, saving N-1 crossings, not N of
+// them executing at once.
+func runQueryBatch(querier Querier, reqs []json.RawMessage, gasLimit uint64) []json.RawMessage {
+	results := make([]json.RawMessage, len(reqs))
+	remaining := int64(gasLimit)
+
+	for i, req := range reqs {
+		budget := remaining
+		if budget < 0 {
+			budget = 0
+		}
+
+		gasBefore := querier.GasConsumed()
+		out := types.RustQuery(querier, req, uint64(budget))
+		gasAfter := querier.GasConsumed()
+		remaining -= int64(gasAfter - gasBefore)
+
+		bz, err := json.Marshal(out)
+		if err != nil {
+			// Unlike cQueryExternal (which can report this via its own
+			// errOut/GoResult_Other), there's no per-element error slot in
+			// the batch array to put this in without breaking the "every
+			// element is a QuerierResult" contract above, so let it propagate
+			// to cQueryExternalBatch's own recoverPanic. A well-formed
+			// QuerierResult should never actually fail to marshal.
+			panic(err)
+		}
+		results[i] = bz
+	}
+
+	return results
+}